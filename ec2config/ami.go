@@ -0,0 +1,104 @@
+package ec2config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// AMIResolver resolves the ImageID (and metadata about it) to launch for a
+// Config. Implementations may hit SSM Parameter Store, EC2 DescribeImages,
+// or simply return a pinned AMI ID.
+type AMIResolver interface {
+	Resolve(ctx context.Context, cfg *Config) (ResolvedAMI, error)
+}
+
+// ResolvedAMI is the metadata recorded back into Config once an AMI has
+// been resolved, so the Sync'd YAML shows exactly what was launched.
+type ResolvedAMI struct {
+	ImageID      string `json:"image-id"`
+	CreationDate string `json:"creation-date"`
+	Kernel       string `json:"kernel"`
+	Architecture string `json:"architecture"`
+}
+
+// StaticAMIResolver returns Config.ImageID unmodified. This is the default
+// resolver when ImageResolver is empty or "static".
+type StaticAMIResolver struct{}
+
+// Resolve implements AMIResolver.
+func (StaticAMIResolver) Resolve(_ context.Context, cfg *Config) (ResolvedAMI, error) {
+	if cfg.ImageID == "" {
+		return ResolvedAMI{}, errors.New("static AMI resolver requires ImageID")
+	}
+	return ResolvedAMI{ImageID: cfg.ImageID}, nil
+}
+
+// SSMParameterGetter is the subset of the SSM client the SSM AMI resolver
+// needs, so it can be faked in tests without a real AWS session.
+type SSMParameterGetter interface {
+	GetAMIParameter(ctx context.Context, name string) (imageID string, err error)
+}
+
+// SSMAMIResolver resolves an AMI ID from an SSM public parameter, e.g.
+// "/aws/service/eks/optimized-ami/<k8s>/amazon-linux-2/recommended/image_id"
+// or "/aws/service/canonical/ubuntu/server/...".
+type SSMAMIResolver struct {
+	Client SSMParameterGetter
+}
+
+// Resolve implements AMIResolver.
+func (r SSMAMIResolver) Resolve(ctx context.Context, cfg *Config) (ResolvedAMI, error) {
+	if cfg.ImageSSMParameter == "" {
+		return ResolvedAMI{}, errors.New("SSM AMI resolver requires ImageSSMParameter")
+	}
+	imageID, err := r.Client.GetAMIParameter(ctx, cfg.ImageSSMParameter)
+	if err != nil {
+		return ResolvedAMI{}, fmt.Errorf("failed to resolve AMI from SSM parameter %q (%v)", cfg.ImageSSMParameter, err)
+	}
+	return ResolvedAMI{ImageID: imageID}, nil
+}
+
+// ImageDescriber is the subset of the EC2 client the DescribeImages AMI
+// resolver needs.
+type ImageDescriber interface {
+	DescribeImagesByNameFilter(ctx context.Context, owners []string, nameFilter string) ([]ResolvedAMI, error)
+}
+
+// DescribeImagesAMIResolver resolves the newest AMI matching ImageOwners
+// and ImageNameFilter via EC2 DescribeImages.
+type DescribeImagesAMIResolver struct {
+	Client ImageDescriber
+}
+
+// Resolve implements AMIResolver.
+func (r DescribeImagesAMIResolver) Resolve(ctx context.Context, cfg *Config) (ResolvedAMI, error) {
+	if len(cfg.ImageOwners) == 0 || cfg.ImageNameFilter == "" {
+		return ResolvedAMI{}, errors.New("DescribeImages AMI resolver requires ImageOwners and ImageNameFilter")
+	}
+	images, err := r.Client.DescribeImagesByNameFilter(ctx, cfg.ImageOwners, cfg.ImageNameFilter)
+	if err != nil {
+		return ResolvedAMI{}, fmt.Errorf("failed to describe images (%v)", err)
+	}
+	if len(images) == 0 {
+		return ResolvedAMI{}, fmt.Errorf("no AMI matched owners %v name filter %q", cfg.ImageOwners, cfg.ImageNameFilter)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].CreationDate > images[j].CreationDate })
+	return images[0], nil
+}
+
+// ResolveAMI resolves cfg.ImageID (and AMI metadata) using resolver, and
+// records the result onto cfg so it is persisted by Sync.
+func (cfg *Config) ResolveAMI(ctx context.Context, resolver AMIResolver) error {
+	resolved, err := resolver.Resolve(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if resolved.ImageID == "" {
+		return fmt.Errorf("AMI resolver %T returned an empty ImageID", resolver)
+	}
+	cfg.ImageID = resolved.ImageID
+	cfg.ResolvedAMI = resolved
+	return nil
+}
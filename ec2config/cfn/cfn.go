@@ -0,0 +1,376 @@
+// Package cfn renders and reconciles the CloudFormation stack that backs
+// an ec2config.Config: VPC with per-AZ public/private subnets, an internet
+// gateway, a NAT gateway, route tables, security groups, instance profile,
+// and key pair, as a single atomic, rollback-safe unit, replacing the
+// previous piecemeal SDK calls.
+package cfn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-k8s-tester/ec2config"
+	"sigs.k8s.io/yaml"
+)
+
+// Stack is the subset of the CloudFormation client the reconciler needs.
+type Stack interface {
+	CreateStack(ctx context.Context, name, templateBody string) (stackARN string, err error)
+	UpdateStack(ctx context.Context, name, templateBody string) error
+	DeleteStack(ctx context.Context, name string) error
+	DescribeStack(ctx context.Context, name string) (Description, error)
+}
+
+// Description is the subset of a CloudFormation stack description the
+// reconciler reads back into Config.
+type Description struct {
+	StackARN string
+	Status   string
+	Outputs  map[string]string
+}
+
+// renderData is what vpcTemplate executes against: cfg itself, plus
+// template-only fields that need Go-side preprocessing (reading the
+// instance profile policy document off disk) that a plain template
+// function cannot do.
+type renderData struct {
+	*ec2config.Config
+	InstanceRolePolicyDocument string
+}
+
+// Render renders the VPC/security-group/instance-profile/key-pair
+// CloudFormation template for cfg.
+func Render(cfg *ec2config.Config) (string, error) {
+	data := renderData{Config: cfg}
+	if cfg.InstanceProfileFilePath != "" {
+		policyYAML, err := instanceRolePolicyYAML(cfg.InstanceProfileFilePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to load instance profile policy %q (%v)", cfg.InstanceProfileFilePath, err)
+		}
+		data.InstanceRolePolicyDocument = policyYAML
+	}
+
+	// IngressRulesTCP keys are either a single port ("22") or a port
+	// range ("2379-2380").
+	funcs := template.FuncMap{
+		"fromPort": func(r string) string { return strings.SplitN(r, "-", 2)[0] },
+		"toPort": func(r string) string {
+			ss := strings.SplitN(r, "-", 2)
+			return ss[len(ss)-1]
+		},
+	}
+	tpl := template.Must(template.New("ec2-vpc").Funcs(funcs).Parse(vpcTemplate))
+	buf := bytes.NewBuffer(nil)
+	if err := tpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// instanceRolePolicyYAML reads the JSON IAM policy document at p and
+// re-renders it as YAML, indented to embed as InstanceRole's inline
+// "Policies[].PolicyDocument" in vpcTemplate.
+func instanceRolePolicyYAML(p string) (string, error) {
+	raw, err := ioutil.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	var doc interface{}
+	if err = json.Unmarshal(raw, &doc); err != nil {
+		return "", fmt.Errorf("not a valid JSON policy document (%v)", err)
+	}
+	y, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return indentBlock(strings.TrimRight(string(y), "\n"), "          "), nil
+}
+
+// indentBlock prefixes every line of s with prefix, for embedding a YAML
+// document inline at a fixed indentation inside vpcTemplate.
+func indentBlock(s string, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = prefix + l
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CreateOrUpdate renders cfg into a template, and creates the stack if it
+// does not exist yet or updates it in place otherwise, recording the
+// result (CFNStackARN, CFNStackStatus, CFNTemplateBody) onto cfg.
+func CreateOrUpdate(ctx context.Context, client Stack, cfg *ec2config.Config) error {
+	body, err := Render(cfg)
+	if err != nil {
+		return err
+	}
+	cfg.CFNTemplateBody = body
+
+	if cfg.CFNStackARN == "" {
+		arn, err := client.CreateStack(ctx, cfg.CFNStackName, body)
+		if err != nil {
+			return fmt.Errorf("failed to create stack %q (%v)", cfg.CFNStackName, err)
+		}
+		cfg.CFNStackARN = arn
+	} else if err = client.UpdateStack(ctx, cfg.CFNStackName, body); err != nil {
+		return fmt.Errorf("failed to update stack %q (%v)", cfg.CFNStackName, err)
+	}
+
+	desc, err := client.DescribeStack(ctx, cfg.CFNStackName)
+	if err != nil {
+		return fmt.Errorf("failed to describe stack %q (%v)", cfg.CFNStackName, err)
+	}
+	cfg.CFNStackARN = desc.StackARN
+	cfg.CFNStackStatus = desc.Status
+	applyOutputs(cfg, desc.Outputs)
+	return nil
+}
+
+// Delete tears down the stack backing cfg, if any.
+func Delete(ctx context.Context, client Stack, cfg *ec2config.Config) error {
+	if cfg.CFNStackARN == "" {
+		return nil
+	}
+	if err := client.DeleteStack(ctx, cfg.CFNStackName); err != nil {
+		return fmt.Errorf("failed to delete stack %q (%v)", cfg.CFNStackName, err)
+	}
+	cfg.CFNStackARN = ""
+	cfg.CFNStackStatus = "DELETE_COMPLETE"
+	return nil
+}
+
+// subnetOutputNames are the template's per-subnet output key prefixes, in
+// the order their CIDRs are cut from VPCCIDR in vpcTemplate.
+var subnetOutputNames = []string{"PublicSubnet1", "PublicSubnet2", "PrivateSubnet1", "PrivateSubnet2"}
+
+// applyOutputs populates the VPC/subnet/security-group/instance-profile
+// fields of cfg from the stack's declared Outputs.
+func applyOutputs(cfg *ec2config.Config, outputs map[string]string) {
+	if v, ok := outputs["VPCID"]; ok {
+		cfg.VPCID = v
+	}
+	if v, ok := outputs["InternetGatewayID"]; ok {
+		cfg.InternetGatewayID = v
+	}
+	if v, ok := outputs["NatGatewayID"]; ok {
+		cfg.NatGatewayID = v
+	}
+	if v, ok := outputs["InstanceProfileName"]; ok {
+		cfg.InstanceProfileName = v
+	}
+	cfg.RouteTableIDs = nil
+	cfg.SecurityGroupIDs = nil
+	for k, v := range outputs {
+		switch {
+		case k == "PublicRouteTableID" || k == "PrivateRouteTableID":
+			cfg.RouteTableIDs = append(cfg.RouteTableIDs, v)
+		case k == "InstanceSecurityGroupID":
+			cfg.SecurityGroupIDs = append(cfg.SecurityGroupIDs, v)
+		}
+	}
+
+	cfg.SubnetIDs = nil
+	cfg.SubnetIDToAvailabilityZone = make(map[string]string)
+	for _, name := range subnetOutputNames {
+		id, ok := outputs[name+"ID"]
+		if !ok {
+			continue
+		}
+		cfg.SubnetIDs = append(cfg.SubnetIDs, id)
+		if az, ok := outputs[name+"AZ"]; ok {
+			cfg.SubnetIDToAvailabilityZone[id] = az
+		}
+	}
+}
+
+const vpcTemplate = `AWSTemplateFormatVersion: '2010-09-09'
+Description: 'aws-k8s-tester EC2 VPC/IAM stack for {{.ClusterName}}'
+Resources:
+  VPC:
+    Type: AWS::EC2::VPC
+    Properties:
+      CidrBlock: '{{.VPCCIDR}}'
+      EnableDnsSupport: true
+      EnableDnsHostnames: true
+      Tags:
+      - Key: Name
+        Value: '{{.ClusterName}}-vpc'
+  InternetGateway:
+    Type: AWS::EC2::InternetGateway
+  VPCGatewayAttachment:
+    Type: AWS::EC2::VPCGatewayAttachment
+    Properties:
+      VpcId: !Ref VPC
+      InternetGatewayId: !Ref InternetGateway
+  PublicSubnet1:
+    Type: AWS::EC2::Subnet
+    Properties:
+      VpcId: !Ref VPC
+      AvailabilityZone: !Select [0, !GetAZs '']
+      CidrBlock: !Select [0, !Cidr [!GetAtt VPC.CidrBlock, 4, 8]]
+      MapPublicIpOnLaunch: true
+      Tags:
+      - Key: Name
+        Value: '{{.ClusterName}}-public-1'
+  PublicSubnet2:
+    Type: AWS::EC2::Subnet
+    Properties:
+      VpcId: !Ref VPC
+      AvailabilityZone: !Select [1, !GetAZs '']
+      CidrBlock: !Select [1, !Cidr [!GetAtt VPC.CidrBlock, 4, 8]]
+      MapPublicIpOnLaunch: true
+      Tags:
+      - Key: Name
+        Value: '{{.ClusterName}}-public-2'
+  PrivateSubnet1:
+    Type: AWS::EC2::Subnet
+    Properties:
+      VpcId: !Ref VPC
+      AvailabilityZone: !Select [0, !GetAZs '']
+      CidrBlock: !Select [2, !Cidr [!GetAtt VPC.CidrBlock, 4, 8]]
+      Tags:
+      - Key: Name
+        Value: '{{.ClusterName}}-private-1'
+  PrivateSubnet2:
+    Type: AWS::EC2::Subnet
+    Properties:
+      VpcId: !Ref VPC
+      AvailabilityZone: !Select [1, !GetAZs '']
+      CidrBlock: !Select [3, !Cidr [!GetAtt VPC.CidrBlock, 4, 8]]
+      Tags:
+      - Key: Name
+        Value: '{{.ClusterName}}-private-2'
+  PublicRouteTable:
+    Type: AWS::EC2::RouteTable
+    Properties:
+      VpcId: !Ref VPC
+  PublicRoute:
+    Type: AWS::EC2::Route
+    DependsOn: VPCGatewayAttachment
+    Properties:
+      RouteTableId: !Ref PublicRouteTable
+      DestinationCidrBlock: '0.0.0.0/0'
+      GatewayId: !Ref InternetGateway
+  PublicSubnet1RouteTableAssociation:
+    Type: AWS::EC2::SubnetRouteTableAssociation
+    Properties:
+      SubnetId: !Ref PublicSubnet1
+      RouteTableId: !Ref PublicRouteTable
+  PublicSubnet2RouteTableAssociation:
+    Type: AWS::EC2::SubnetRouteTableAssociation
+    Properties:
+      SubnetId: !Ref PublicSubnet2
+      RouteTableId: !Ref PublicRouteTable
+  NatEIP:
+    Type: AWS::EC2::EIP
+    Properties:
+      Domain: vpc
+  NatGateway:
+    Type: AWS::EC2::NatGateway
+    DependsOn: VPCGatewayAttachment
+    Properties:
+      AllocationId: !GetAtt NatEIP.AllocationId
+      SubnetId: !Ref PublicSubnet1
+      Tags:
+      - Key: Name
+        Value: '{{.ClusterName}}-nat'
+  PrivateRouteTable:
+    Type: AWS::EC2::RouteTable
+    Properties:
+      VpcId: !Ref VPC
+  PrivateRoute:
+    Type: AWS::EC2::Route
+    Properties:
+      RouteTableId: !Ref PrivateRouteTable
+      DestinationCidrBlock: '0.0.0.0/0'
+      NatGatewayId: !Ref NatGateway
+  PrivateSubnet1RouteTableAssociation:
+    Type: AWS::EC2::SubnetRouteTableAssociation
+    Properties:
+      SubnetId: !Ref PrivateSubnet1
+      RouteTableId: !Ref PrivateRouteTable
+  PrivateSubnet2RouteTableAssociation:
+    Type: AWS::EC2::SubnetRouteTableAssociation
+    Properties:
+      SubnetId: !Ref PrivateSubnet2
+      RouteTableId: !Ref PrivateRouteTable
+  InstanceSecurityGroup:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      GroupDescription: 'aws-k8s-tester {{.ClusterName}} instance security group'
+      VpcId: !Ref VPC
+      SecurityGroupIngress:
+{{- range $port, $cidr := .IngressRulesTCP}}
+      - IpProtocol: tcp
+        FromPort: {{fromPort $port}}
+        ToPort: {{toPort $port}}
+        CidrIp: '{{$cidr}}'
+{{- end}}
+{{- if .InstanceProfileFilePath}}
+  InstanceRole:
+    Type: AWS::IAM::Role
+    Properties:
+      RoleName: '{{.InstanceProfileRoleName}}'
+      AssumeRolePolicyDocument:
+        Version: '2012-10-17'
+        Statement:
+        - Effect: Allow
+          Principal:
+            Service: ec2.amazonaws.com
+          Action: sts:AssumeRole
+      Policies:
+      - PolicyName: '{{.InstanceProfilePolicyName}}'
+        PolicyDocument:
+{{.InstanceRolePolicyDocument}}
+  InstanceProfile:
+    Type: AWS::IAM::InstanceProfile
+    Properties:
+      InstanceProfileName: '{{.InstanceProfileName}}'
+      Roles:
+      - !Ref InstanceRole
+{{- end}}
+{{- if not .KeyCreateSkip}}
+  KeyPair:
+    Type: AWS::EC2::KeyPair
+    Properties:
+      KeyName: '{{.KeyName}}'
+{{- end}}
+Outputs:
+  VPCID:
+    Value: !Ref VPC
+  InternetGatewayID:
+    Value: !Ref InternetGateway
+  PublicSubnet1ID:
+    Value: !Ref PublicSubnet1
+  PublicSubnet1AZ:
+    Value: !GetAtt PublicSubnet1.AvailabilityZone
+  PublicSubnet2ID:
+    Value: !Ref PublicSubnet2
+  PublicSubnet2AZ:
+    Value: !GetAtt PublicSubnet2.AvailabilityZone
+  PrivateSubnet1ID:
+    Value: !Ref PrivateSubnet1
+  PrivateSubnet1AZ:
+    Value: !GetAtt PrivateSubnet1.AvailabilityZone
+  PrivateSubnet2ID:
+    Value: !Ref PrivateSubnet2
+  PrivateSubnet2AZ:
+    Value: !GetAtt PrivateSubnet2.AvailabilityZone
+  PublicRouteTableID:
+    Value: !Ref PublicRouteTable
+  PrivateRouteTableID:
+    Value: !Ref PrivateRouteTable
+  NatGatewayID:
+    Value: !Ref NatGateway
+  InstanceSecurityGroupID:
+    Value: !GetAtt InstanceSecurityGroup.GroupId
+{{- if .InstanceProfileFilePath}}
+  InstanceProfileName:
+    Value: !Ref InstanceProfile
+{{- end}}
+`
@@ -0,0 +1,92 @@
+package cfn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-k8s-tester/ec2config"
+)
+
+func outputsFixture() map[string]string {
+	return map[string]string{
+		"VPCID":                   "vpc-1",
+		"InternetGatewayID":       "igw-1",
+		"NatGatewayID":            "nat-1",
+		"PublicRouteTableID":      "rtb-public",
+		"PrivateRouteTableID":     "rtb-private",
+		"InstanceSecurityGroupID": "sg-1",
+		"PublicSubnet1ID":         "subnet-pub-1",
+		"PublicSubnet1AZ":         "us-east-1a",
+		"PublicSubnet2ID":         "subnet-pub-2",
+		"PublicSubnet2AZ":         "us-east-1b",
+		"PrivateSubnet1ID":        "subnet-priv-1",
+		"PrivateSubnet1AZ":        "us-east-1a",
+		"PrivateSubnet2ID":        "subnet-priv-2",
+		"PrivateSubnet2AZ":        "us-east-1b",
+	}
+}
+
+func TestApplyOutputsTwiceDoesNotDuplicate(t *testing.T) {
+	cfg := &ec2config.Config{}
+	outputs := outputsFixture()
+
+	applyOutputs(cfg, outputs)
+	applyOutputs(cfg, outputs)
+
+	if len(cfg.RouteTableIDs) != 2 {
+		t.Fatalf("expected 2 RouteTableIDs after two applyOutputs calls, got %v", cfg.RouteTableIDs)
+	}
+	if len(cfg.SecurityGroupIDs) != 1 {
+		t.Fatalf("expected 1 SecurityGroupID after two applyOutputs calls, got %v", cfg.SecurityGroupIDs)
+	}
+	if len(cfg.SubnetIDs) != 4 {
+		t.Fatalf("expected 4 SubnetIDs after two applyOutputs calls, got %v", cfg.SubnetIDs)
+	}
+}
+
+// fakeStack is a minimal in-memory Stack for exercising CreateOrUpdate's
+// create-then-reconcile path without a real CloudFormation client.
+type fakeStack struct {
+	created bool
+	outputs map[string]string
+}
+
+func (f *fakeStack) CreateStack(_ context.Context, _, _ string) (string, error) {
+	f.created = true
+	return "arn:aws:cloudformation:us-east-1:000000000000:stack/test/abc", nil
+}
+
+func (f *fakeStack) UpdateStack(_ context.Context, _, _ string) error {
+	return nil
+}
+
+func (f *fakeStack) DeleteStack(_ context.Context, _ string) error {
+	return nil
+}
+
+func (f *fakeStack) DescribeStack(_ context.Context, _ string) (Description, error) {
+	return Description{
+		StackARN: "arn:aws:cloudformation:us-east-1:000000000000:stack/test/abc",
+		Status:   "CREATE_COMPLETE",
+		Outputs:  f.outputs,
+	}, nil
+}
+
+func TestCreateOrUpdateTwiceDoesNotDuplicateOutputs(t *testing.T) {
+	client := &fakeStack{outputs: outputsFixture()}
+	cfg := &ec2config.Config{ClusterName: "test", CFNStackName: "test-vpc"}
+
+	if err := CreateOrUpdate(context.Background(), client, cfg); err != nil {
+		t.Fatalf("first CreateOrUpdate failed: %v", err)
+	}
+	if err := CreateOrUpdate(context.Background(), client, cfg); err != nil {
+		t.Fatalf("second CreateOrUpdate failed: %v", err)
+	}
+
+	if len(cfg.RouteTableIDs) != 2 {
+		t.Fatalf("expected 2 RouteTableIDs after two CreateOrUpdate calls, got %v", cfg.RouteTableIDs)
+	}
+	if len(cfg.SecurityGroupIDs) != 1 {
+		t.Fatalf("expected 1 SecurityGroupID after two CreateOrUpdate calls, got %v", cfg.SecurityGroupIDs)
+	}
+}
@@ -2,10 +2,13 @@
 package ec2config
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
+	"math/big"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -15,6 +18,8 @@ import (
 
 	"github.com/aws/aws-k8s-tester/ec2config/plugins"
 	pkgaws "github.com/aws/aws-k8s-tester/pkg/aws"
+	"github.com/aws/aws-k8s-tester/pkg/awsapi"
+	"github.com/aws/aws-k8s-tester/pkg/ebsutil"
 	"github.com/aws/aws-k8s-tester/pkg/logutil"
 	"sigs.k8s.io/yaml"
 )
@@ -29,6 +34,11 @@ type Config struct {
 	AWSAccountID string `json:"aws-account-id"`
 	// AWSRegion is the AWS region.
 	AWSRegion string `json:"aws-region"`
+	// UseFIPSEndpoint routes every AWS API call this tester makes (EKS,
+	// EC2, IAM, CloudFormation, S3, SSM) through its FIPS 140-2 validated
+	// endpoint instead of the standard one. Only a subset of regions
+	// publish a FIPS EKS endpoint; see pkg/awsapi.ValidateFIPSEndpointRegion.
+	UseFIPSEndpoint bool `json:"use-fips-endpoint"`
 
 	// LogLevel configures log level. Only supports debug, info, warn, error, panic, or fatal. Default 'info'.
 	LogLevel string `json:"log-level"`
@@ -74,7 +84,36 @@ type Config struct {
 	UpdatedAt        time.Time `json:"updated-at"`         // read-only to user
 
 	// ImageID is the Amazon Machine Image (AMI).
+	// If "ImageResolver" is set to anything other than "static", this is
+	// overwritten with the resolved AMI ID.
 	ImageID string `json:"image-id"`
+	// ImageResolver selects how "ImageID" is resolved: "static" (use
+	// "ImageID" as-is, the default), "ssm" (look up "ImageSSMParameter"),
+	// or "describe-images" (newest AMI matching "ImageOwners" and
+	// "ImageNameFilter").
+	ImageResolver string `json:"image-resolver"`
+	// ImageSSMParameter is the SSM public parameter name to resolve an AMI
+	// ID from, e.g.
+	// "/aws/service/eks/optimized-ami/1.21/amazon-linux-2/recommended/image_id".
+	// Used when "ImageResolver" is "ssm".
+	ImageSSMParameter string `json:"image-ssm-parameter"`
+	// ImageNameFilter is the EC2 DescribeImages "name" filter pattern
+	// (e.g. "amazon-eks-node-1.21-v*"). Used when "ImageResolver" is
+	// "describe-images".
+	ImageNameFilter string `json:"image-name-filter"`
+	// ImageOwners is the list of AMI owner account IDs/aliases to restrict
+	// DescribeImages to (e.g. "amazon", "099720109477" for Canonical).
+	// Used when "ImageResolver" is "describe-images".
+	ImageOwners []string `json:"image-owners"`
+	// KubernetesVersion is the Kubernetes minor version of the
+	// EKS-optimized AMI. It is stored with the config only: no resolver
+	// substitutes it into "ImageSSMParameter", which is looked up verbatim,
+	// so the version must already be written into that parameter name.
+	KubernetesVersion string `json:"kubernetes-version"`
+	// ResolvedAMI records the AMI metadata "ImageResolver" returned.
+	// Read-only to the user.
+	ResolvedAMI ResolvedAMI `json:"resolved-ami"`
+
 	// UserName is the user name used for running init scripts or SSH access.
 	UserName string `json:"user-name"`
 	// Plugins is the list of plugins.
@@ -99,6 +138,26 @@ type Config struct {
 	// ClusterSize is the number of EC2 instances to create.
 	ClusterSize int `json:"cluster-size"`
 
+	// SpotPrice is the maximum hourly price to pay for a Spot instance.
+	// Leave empty to pay up to the On-Demand price, which is the default
+	// for the On-Demand share of a fleet.
+	SpotPrice string `json:"spot-price"`
+	// SpotInstanceType is the Spot request behavior, "one-time" or
+	// "persistent". Leave empty to launch On-Demand only.
+	SpotInstanceType string `json:"spot-instance-type"`
+	// OnDemandBaseCapacity is the minimum number of instances launched as
+	// On-Demand before any Spot capacity is requested.
+	OnDemandBaseCapacity int64 `json:"on-demand-base-capacity"`
+	// OnDemandPercentageAboveBase is the percentage of capacity above
+	// OnDemandBaseCapacity that must be On-Demand, 0-100.
+	OnDemandPercentageAboveBase int64 `json:"on-demand-percentage-above-base"`
+	// SpotAllocationStrategy is "lowest-price" or "capacity-optimized".
+	SpotAllocationStrategy string `json:"spot-allocation-strategy"`
+	// InstanceTypesOverride is a list of instance types to launch a mixed
+	// fleet across, so ClusterSize can be satisfied from whichever types
+	// and AZs have capacity. Leave empty to only launch InstanceType.
+	InstanceTypesOverride []string `json:"instance-types-override"`
+
 	// KeyName is the name of the key pair used for SSH access.
 	// Leave empty to create a temporary one.
 	KeyName string `json:"key-name"`
@@ -121,9 +180,26 @@ type Config struct {
 	VPCCreated bool `json:"vpc-created"`
 	// InternetGatewayID is the internet gateway ID.
 	InternetGatewayID string `json:"internet-gateway-id"`
+	// NatGatewayID is the NAT gateway ID used to route the private
+	// subnets' outbound traffic.
+	NatGatewayID string `json:"nat-gateway-id"`
 	// RouteTableIDs is the list of route table IDs.
 	RouteTableIDs []string `json:"route-table-ids"`
 
+	// CFNStackName is the name of the CloudFormation stack that
+	// provisions the VPC, subnets, security groups, instance profile, and
+	// key pair as a single atomic unit. Leave empty to default to
+	// "<ClusterName>-vpc".
+	CFNStackName string `json:"cfn-stack-name"`
+	// CFNStackARN is the ARN of the created CloudFormation stack.
+	CFNStackARN string `json:"cfn-stack-arn"`
+	// CFNStackStatus is the last observed CloudFormation stack status
+	// (e.g. "CREATE_COMPLETE", "UPDATE_COMPLETE"). Read-only to the user.
+	CFNStackStatus string `json:"cfn-stack-status"`
+	// CFNTemplateBody is the rendered CloudFormation template last
+	// submitted for this stack. Read-only to the user.
+	CFNTemplateBody string `json:"cfn-template-body"`
+
 	// SubnetIDs is a list of subnet IDs to use.
 	// If empty, it will fetch subnets from a given or created VPC.
 	// And randomly assign them to instances.
@@ -152,6 +228,26 @@ type Config struct {
 	// a volume size, the default is the snapshot size.
 	VolumeSize int64 `json:"volume-size"`
 
+	// BlockDeviceMappings is the full set of EBS volumes to attach,
+	// mirroring the EC2 RunInstances "BlockDeviceMappings" API shape. If
+	// empty, a single root volume is derived from "VolumeSize" (and
+	// "EncryptRootVolume"/"KmsKeyID" if set).
+	BlockDeviceMappings []BlockDeviceMappingSpec `json:"block-device-mappings"`
+	// EncryptRootVolume is true to force-encrypt the root volume, even if
+	// the AMI's backing snapshot is unencrypted. Ignored if
+	// "BlockDeviceMappings" already configures the root device.
+	EncryptRootVolume bool `json:"encrypt-root-volume"`
+	// KmsKeyID is the KMS key used to encrypt the root volume when
+	// "EncryptRootVolume" is true. Leave empty to use the AWS managed
+	// "aws/ebs" key.
+	KmsKeyID string `json:"kms-key-id"`
+
+	// MetadataOptions configures the Instance Metadata Service (IMDS) for
+	// launched instances. Defaults to requiring IMDSv2 with a hop limit of
+	// 2, the minimum needed for Kubernetes pods to reach IMDS through the
+	// container network.
+	MetadataOptions MetadataOptions `json:"metadata-options"`
+
 	// Instances is a set of EC2 instances created from this configuration.
 	Instances map[string]Instance `json:"instances"`
 
@@ -180,6 +276,38 @@ type Config struct {
 
 	// CustomScript is executed at the end of EC2 init script.
 	CustomScript string `json:"custom-script"`
+
+	// LaunchTemplateName is the EC2 Launch Template backing instance
+	// creation. Leave empty to default to "<ClusterName>-lt".
+	LaunchTemplateName string `json:"launch-template-name"`
+	// LaunchTemplateID is the ID of the created Launch Template.
+	LaunchTemplateID string `json:"launch-template-id"`
+	// LaunchTemplateVersion is the latest Launch Template version number.
+	// It is bumped automatically whenever a field that affects instance
+	// creation ("InitScript", "InstanceType", "BlockDeviceMappings",
+	// "SecurityGroupIDs", "MetadataOptions") changes after the Launch Template has already
+	// been created, so instances are always launched from a version that
+	// matches the current config.
+	LaunchTemplateVersion int64 `json:"launch-template-version"`
+	// LaunchTemplateCreated is true once the Launch Template has been
+	// created, so the next field change creates a new version instead of
+	// a new template.
+	LaunchTemplateCreated bool `json:"launch-template-created"`
+
+	// launchTemplateFingerprint is an unexported snapshot, captured by
+	// Load, of the fields that affect the rendered Launch Template. It is
+	// compared against the current values in ValidateAndSetDefaults to
+	// decide whether a new Launch Template version is required.
+	launchTemplateFingerprint string
+}
+
+// launchTemplateFingerprint summarizes the Config fields that feed into
+// the rendered EC2 Launch Template version.
+func launchTemplateFingerprint(cfg *Config) string {
+	bdm, _ := json.Marshal(cfg.BlockDeviceMappings)
+	sg, _ := json.Marshal(cfg.SecurityGroupIDs)
+	mo, _ := json.Marshal(cfg.MetadataOptions)
+	return cfg.InitScript + "|" + cfg.InstanceType + "|" + string(bdm) + "|" + string(sg) + "|" + string(mo)
 }
 
 // Instance represents an EC2 instance.
@@ -202,6 +330,15 @@ type Instance struct {
 	RootDeviceType      string               `json:"root-device-type"`
 	SecurityGroups      []SecurityGroup      `json:"security-groups"`
 	LaunchTime          time.Time            `json:"launch-time"`
+
+	// CapacityType is "spot" or "on-demand", recording how this instance
+	// was allocated by the fleet request.
+	CapacityType string `json:"capacity-type"`
+	// SpotInstanceRequestID is set when CapacityType is "spot".
+	SpotInstanceRequestID string `json:"spot-instance-request-id"`
+	// InterruptionNotices records any Spot interruption notices observed
+	// via IMDS for this instance (e.g. "marked-for-termination").
+	InterruptionNotices []string `json:"interruption-notices"`
 }
 
 // Placement defines EC2 placement.
@@ -229,6 +366,50 @@ type EBS struct {
 	VolumeID            string `json:"volume-id"`
 }
 
+// BlockDeviceMappingSpec is a user-configured EBS volume to attach at
+// launch, mirroring the EC2 RunInstances "BlockDeviceMappings" API shape.
+type BlockDeviceMappingSpec struct {
+	// DeviceName is the device name exposed to the instance (e.g.
+	// "/dev/xvda" for the root volume, "/dev/xvdb" for a data volume).
+	DeviceName string `json:"device-name"`
+	// VolumeType is "gp2", "gp3", "io1", "io2", "st1", "sc1", or "standard".
+	VolumeType string `json:"volume-type"`
+	// VolumeSize is the volume size, in GiB.
+	VolumeSize int64 `json:"volume-size"`
+	// Iops is the number of provisioned IOPS. Required for "io1"/"io2",
+	// optional for "gp3" (defaults to the gp3 baseline).
+	Iops int64 `json:"iops"`
+	// Throughput is the provisioned throughput, in MiB/s. Only valid for
+	// "gp3".
+	Throughput int64 `json:"throughput"`
+	// Encrypted is true to encrypt this volume.
+	Encrypted bool `json:"encrypted"`
+	// KmsKeyID is the KMS key used to encrypt this volume when
+	// "Encrypted" is true. Leave empty to use the AWS managed "aws/ebs" key.
+	KmsKeyID string `json:"kms-key-id"`
+	// DeleteOnTermination is true to delete this volume when the instance
+	// is terminated.
+	DeleteOnTermination bool `json:"delete-on-termination"`
+	// SnapshotID creates the volume from an existing EBS snapshot.
+	SnapshotID string `json:"snapshot-id"`
+}
+
+// MetadataOptions configures the Instance Metadata Service (IMDS), mirroring
+// the EC2 RunInstances "MetadataOptions" API shape.
+type MetadataOptions struct {
+	// HTTPTokens is "required" (IMDSv2 only) or "optional" (IMDSv1 and
+	// IMDSv2 both allowed).
+	HTTPTokens string `json:"http-tokens"`
+	// HTTPPutResponseHopLimit is the number of network hops an IMDS
+	// request/response is allowed to make. Kubernetes pods reaching IMDS
+	// through the container network need at least 2.
+	HTTPPutResponseHopLimit int64 `json:"http-put-response-hop-limit"`
+	// HTTPEndpoint is "enabled" or "disabled".
+	HTTPEndpoint string `json:"http-endpoint"`
+	// InstanceMetadataTags is "enabled" or "disabled".
+	InstanceMetadataTags string `json:"instance-metadata-tags"`
+}
+
 // SecurityGroup defines a security group.
 type SecurityGroup struct {
 	GroupName string `json:"group-name"`
@@ -405,12 +586,29 @@ func (cfg *Config) UpdateFromEnvs() error {
 	return nil
 }
 
+// defaultRootDeviceName is the root device name Amazon Linux 2 and most
+// EKS-optimized AMIs expose, used when materializing a root
+// BlockDeviceMappingSpec for EncryptRootVolume/KmsKeyID.
+const defaultRootDeviceName = "/dev/xvda"
+
 // genTag generates a tag for cluster name, CloudFormation, and S3 bucket.
 func genTag() string {
 	now := time.Now()
 	return fmt.Sprintf("ec2-%d%02d%02d%02d", now.Year()-2000, int(now.Month()), now.Day(), now.Hour())
 }
 
+// validateBlockDeviceMapping rejects IOPS/throughput/type combinations the
+// EBS CreateVolume API would reject.
+func validateBlockDeviceMapping(bd BlockDeviceMappingSpec) error {
+	if err := ebsutil.Validate(fmt.Sprintf("%q", bd.DeviceName), bd.VolumeType, bd.Iops, bd.Throughput); err != nil {
+		return err
+	}
+	if bd.KmsKeyID != "" && !bd.Encrypted {
+		return fmt.Errorf("%q sets KmsKeyID but Encrypted is false", bd.DeviceName)
+	}
+	return nil
+}
+
 // ValidateAndSetDefaults returns an error for invalid configurations.
 // And updates empty fields with default values.
 // At the end, it writes populated YAML to aws-k8s-tester config path.
@@ -424,11 +622,42 @@ func (cfg *Config) ValidateAndSetDefaults() (err error) {
 	if _, ok := pkgaws.RegionToAiport[cfg.AWSRegion]; !ok {
 		return fmt.Errorf("%q not found", cfg.AWSRegion)
 	}
+	if err = awsapi.ValidateFIPSEndpointRegion(cfg.AWSRegion, cfg.UseFIPSEndpoint); err != nil {
+		return err
+	}
 	if cfg.UserName == "" {
 		return errors.New("empty UserName")
 	}
-	if cfg.ImageID == "" {
-		return errors.New("empty ImageID")
+	if cfg.ImageResolver == "" {
+		cfg.ImageResolver = "static"
+	}
+	switch cfg.ImageResolver {
+	case "static":
+		if cfg.ImageID == "" {
+			return errors.New("empty ImageID")
+		}
+		// static resolution needs no AWS client, so drive it here rather
+		// than leaving ImageID/ResolvedAMI population to a caller.
+		if err = cfg.ResolveAMI(context.Background(), StaticAMIResolver{}); err != nil {
+			return err
+		}
+	case "ssm":
+		if cfg.ImageSSMParameter == "" {
+			return errors.New("ImageResolver \"ssm\" requires ImageSSMParameter")
+		}
+		// unlike "static", resolving this needs a live SSM client, which
+		// this package does not construct. The caller must build an
+		// SSMAMIResolver around its own client and call cfg.ResolveAMI
+		// after ValidateAndSetDefaults; until then ImageID stays whatever
+		// the caller last set (possibly empty).
+	case "describe-images":
+		if cfg.ImageNameFilter == "" || len(cfg.ImageOwners) == 0 {
+			return errors.New("ImageResolver \"describe-images\" requires ImageNameFilter and ImageOwners")
+		}
+		// same as "ssm": needs a live EC2 client the caller must supply
+		// via DescribeImagesAMIResolver, then call cfg.ResolveAMI itself.
+	default:
+		return fmt.Errorf("unexpected ImageResolver %q", cfg.ImageResolver)
 	}
 
 	if len(cfg.Plugins) > 0 && !cfg.InitScriptCreated {
@@ -448,12 +677,107 @@ func (cfg *Config) ValidateAndSetDefaults() (err error) {
 		return errors.New("unexpected ClusterSize")
 	}
 
+	if cfg.MetadataOptions.HTTPTokens == "" {
+		cfg.MetadataOptions.HTTPTokens = "required"
+	}
+	if cfg.MetadataOptions.HTTPPutResponseHopLimit == 0 {
+		cfg.MetadataOptions.HTTPPutResponseHopLimit = 2
+	}
+	if cfg.MetadataOptions.HTTPEndpoint == "" {
+		cfg.MetadataOptions.HTTPEndpoint = "enabled"
+	}
+	if cfg.MetadataOptions.InstanceMetadataTags == "" {
+		cfg.MetadataOptions.InstanceMetadataTags = "disabled"
+	}
+	switch cfg.MetadataOptions.HTTPTokens {
+	case "required", "optional":
+	default:
+		return fmt.Errorf("unexpected MetadataOptions.HTTPTokens %q", cfg.MetadataOptions.HTTPTokens)
+	}
+	switch cfg.MetadataOptions.HTTPEndpoint {
+	case "enabled", "disabled":
+	default:
+		return fmt.Errorf("unexpected MetadataOptions.HTTPEndpoint %q", cfg.MetadataOptions.HTTPEndpoint)
+	}
+	switch cfg.MetadataOptions.InstanceMetadataTags {
+	case "enabled", "disabled":
+	default:
+		return fmt.Errorf("unexpected MetadataOptions.InstanceMetadataTags %q", cfg.MetadataOptions.InstanceMetadataTags)
+	}
+	if cfg.MetadataOptions.HTTPPutResponseHopLimit < 1 || cfg.MetadataOptions.HTTPPutResponseHopLimit > 64 {
+		return fmt.Errorf("unexpected MetadataOptions.HTTPPutResponseHopLimit %d", cfg.MetadataOptions.HTTPPutResponseHopLimit)
+	}
+
+	hasRootMapping := false
+	for _, bd := range cfg.BlockDeviceMappings {
+		if bd.DeviceName == defaultRootDeviceName {
+			hasRootMapping = true
+			break
+		}
+	}
+	if !hasRootMapping && (cfg.EncryptRootVolume || cfg.KmsKeyID != "") {
+		// no entry in BlockDeviceMappings configures the root device, so
+		// EncryptRootVolume/KmsKeyID have nothing to attach to yet;
+		// materialize the root device mapping they document themselves
+		// as configuring.
+		cfg.BlockDeviceMappings = append(cfg.BlockDeviceMappings, BlockDeviceMappingSpec{
+			DeviceName: defaultRootDeviceName,
+			VolumeSize: cfg.VolumeSize,
+			Encrypted:  cfg.EncryptRootVolume || cfg.KmsKeyID != "",
+			KmsKeyID:   cfg.KmsKeyID,
+		})
+	}
+	for i, bd := range cfg.BlockDeviceMappings {
+		if bd.VolumeType == "" {
+			bd.VolumeType = "gp3"
+			cfg.BlockDeviceMappings[i] = bd
+		}
+		if err = validateBlockDeviceMapping(bd); err != nil {
+			return err
+		}
+	}
+
+	switch cfg.SpotInstanceType {
+	case "", "one-time", "persistent":
+	default:
+		return fmt.Errorf("unexpected SpotInstanceType %q", cfg.SpotInstanceType)
+	}
+	switch cfg.SpotAllocationStrategy {
+	case "", "lowest-price", "capacity-optimized":
+	default:
+		return fmt.Errorf("unexpected SpotAllocationStrategy %q", cfg.SpotAllocationStrategy)
+	}
+	if cfg.OnDemandBaseCapacity < 0 || cfg.OnDemandBaseCapacity > int64(cfg.ClusterSize) {
+		return fmt.Errorf("OnDemandBaseCapacity %d must be between 0 and ClusterSize %d", cfg.OnDemandBaseCapacity, cfg.ClusterSize)
+	}
+	if cfg.OnDemandPercentageAboveBase < 0 || cfg.OnDemandPercentageAboveBase > 100 {
+		return fmt.Errorf("unexpected OnDemandPercentageAboveBase %d", cfg.OnDemandPercentageAboveBase)
+	}
+	if cfg.SpotInstanceType != "" && cfg.AssociatePublicIPAddress && len(cfg.InstanceTypesOverride) > 0 && len(cfg.SubnetIDs) > 1 {
+		// EC2 Fleet assigns a public IP per network interface, which only
+		// works when every launch template config resolves to a single
+		// subnet/AZ; a multi-subnet mixed-instances fleet cannot request
+		// one here, so callers must associate public IPs post-launch.
+		return errors.New("AssociatePublicIPAddress cannot be combined with InstanceTypesOverride across multiple SubnetIDs")
+	}
+
 	if cfg.Tag == "" {
 		cfg.Tag = genTag()
 	}
 	if cfg.ClusterName == "" {
 		airport := pkgaws.RegionToAiport[cfg.AWSRegion]
-		cfg.ClusterName = cfg.Tag + "-" + strings.ToLower(airport) + "-" + cfg.AWSRegion + "-" + randString(5)
+		// include the AWS account ID and a millisecond-precision timestamp
+		// so concurrent test runs in CI never collide on S3 bucket keys,
+		// IAM role names, or CloudFormation stack names derived from
+		// ClusterName.
+		ts := time.Now().UnixNano() / int64(time.Millisecond)
+		cfg.ClusterName = cfg.Tag + "-" + strings.ToLower(airport) + "-" + cfg.AWSRegion + "-" + fmt.Sprintf("%d", ts) + "-" + randString(5)
+		if cfg.AWSAccountID != "" {
+			cfg.ClusterName += "-" + cfg.AWSAccountID
+		}
+	}
+	if cfg.CFNStackName == "" {
+		cfg.CFNStackName = cfg.ClusterName + "-vpc"
 	}
 
 	if cfg.ConfigPath == "" {
@@ -506,6 +830,15 @@ func (cfg *Config) ValidateAndSetDefaults() (err error) {
 		cfg.InstanceProfilePolicyName = cfg.InstanceProfileName + "-policy"
 	}
 
+	if cfg.LaunchTemplateName == "" {
+		cfg.LaunchTemplateName = cfg.ClusterName + "-lt"
+	}
+	newFingerprint := launchTemplateFingerprint(cfg)
+	if cfg.LaunchTemplateCreated && newFingerprint != cfg.launchTemplateFingerprint {
+		cfg.LaunchTemplateVersion++
+	}
+	cfg.launchTemplateFingerprint = newFingerprint
+
 	return nil
 }
 
@@ -543,6 +876,8 @@ func Load(p string) (cfg *Config, err error) {
 		return nil, err
 	}
 
+	cfg.launchTemplateFingerprint = launchTemplateFingerprint(cfg)
+
 	return cfg, nil
 }
 
@@ -564,6 +899,13 @@ func (cfg *Config) Sync() (err error) {
 	return ioutil.WriteFile(cfg.ConfigPath, d, 0600)
 }
 
+// Endpoint returns the endpoint this tester's AWS clients should use for
+// the given service (e.g. "eks", "ec2", "iam", "cloudformation", "s3",
+// "ssm"), honoring UseFIPSEndpoint.
+func (cfg *Config) Endpoint(service string) string {
+	return awsapi.EndpointFor(service, cfg.AWSRegion, cfg.UseFIPSEndpoint)
+}
+
 // SSHCommands returns the SSH commands.
 func (cfg *Config) SSHCommands() (s string) {
 	s = fmt.Sprintf(`
@@ -596,11 +938,20 @@ scp -i %s -r LOCAL_DIRECTORY_PATH %s@%s:REMOTE_DIRECTORY_PATH
 
 const ll = "0123456789abcdefghijklmnopqrstuvwxyz"
 
+// randString returns a random alphanumeric string of length n, suitable
+// for uniquely naming resources across concurrent test runs. It is backed
+// by crypto/rand rather than a time-seeded math/rand, since seeding with
+// time.Now().UnixNano() inside the generation loop produces identical
+// characters whenever two calls land in the same nanosecond.
 func randString(n int) string {
 	b := make([]byte, n)
+	bound := big.NewInt(int64(len(ll)))
 	for i := range b {
-		rand.Seed(time.Now().UnixNano())
-		b[i] = ll[rand.Intn(len(ll))]
+		idx, err := rand.Int(rand.Reader, bound)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = ll[idx.Int64()]
 	}
 	return string(b)
 }
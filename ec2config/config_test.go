@@ -0,0 +1,29 @@
+package ec2config
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRandStringConcurrentUniqueness(t *testing.T) {
+	const n = 200
+
+	results := make([]string, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			results[i] = randString(5)
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]struct{}, n)
+	for _, s := range results {
+		if _, ok := seen[s]; ok {
+			t.Fatalf("randString(5) produced a duplicate across concurrent calls: %q", s)
+		}
+		seen[s] = struct{}{}
+	}
+}
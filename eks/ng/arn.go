@@ -0,0 +1,24 @@
+package ng
+
+import "github.com/aws/aws-k8s-tester/pkg/arnutil"
+
+// validateConfigMapAuthARNs checks every rolearn/userarn the tester is about
+// to write into the aws-auth ConfigMap against the cluster's configured
+// region, so a cross-partition or (unless explicitly allowed) cross-region
+// ARN fails before any "kubectl apply" rather than mid-provision.
+func (ts *tester) validateConfigMapAuthARNs(roles []RoleMapping, users []UserMapping) error {
+	region := ts.cfg.EKSConfig.Region
+	allowCrossRegion := ts.cfg.EKSConfig.AddOnNodeGroups.AllowCrossRegionARN
+
+	for _, r := range roles {
+		if err := arnutil.ValidateARN(r.RoleARN, region, allowCrossRegion); err != nil {
+			return err
+		}
+	}
+	for _, u := range users {
+		if err := arnutil.ValidateARN(u.UserARN, region, allowCrossRegion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
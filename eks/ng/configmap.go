@@ -3,51 +3,61 @@ package ng
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/aws/aws-k8s-tester/pkg/fileutil"
+	"github.com/aws/aws-k8s-tester/pkg/kubectlutil"
 	"go.uber.org/zap"
 	"k8s.io/utils/exec"
+	"sigs.k8s.io/yaml"
 )
 
+// RoleMapping is a single entry in the aws-auth ConfigMap's "mapRoles" list.
+type RoleMapping struct {
+	RoleARN  string   `json:"rolearn"`
+	Username string   `json:"username"`
+	Groups   []string `json:"groups"`
+}
+
+// UserMapping is a single entry in the aws-auth ConfigMap's "mapUsers" list.
+type UserMapping struct {
+	UserARN  string   `json:"userarn"`
+	Username string   `json:"username"`
+	Groups   []string `json:"groups"`
+}
+
 func (ts *tester) createConfigMap() error {
 	ts.cfg.Logger.Info("writing ConfigMap", zap.String("instance-role-arn", ts.cfg.EKSConfig.AddOnNodeGroups.RoleARN))
-	p, err := writeConfigMapAuth(ts.cfg.EKSConfig.AddOnNodeGroups.RoleARN)
+	roles, users, err := ts.mergeConfigMapAuth()
+	if err != nil {
+		return err
+	}
+	if err = ts.validateConfigMapAuthARNs(roles, users); err != nil {
+		return err
+	}
+	p, err := writeConfigMapAuth(roles, users)
 	if err != nil {
 		return err
 	}
 
 	// might take several minutes for DNS to propagate
-	waitDur := 5 * time.Minute
-	retryStart := time.Now()
-	for time.Now().Sub(retryStart) < waitDur {
-		select {
-		case <-ts.cfg.Stopc:
-			return errors.New("create ConfigMap aborted")
-		case <-ts.cfg.Sig:
-			return errors.New("create ConfigMap aborted")
-		case <-time.After(5 * time.Second):
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-		output, err := exec.New().CommandContext(
-			ctx,
-			ts.cfg.EKSConfig.KubectlPath,
-			"--kubeconfig="+ts.cfg.EKSConfig.KubeConfigPath,
-			"apply", "--filename="+p,
-		).CombinedOutput()
-		cancel()
-		out := string(output)
-		if err != nil {
-			return fmt.Errorf("'kubectl version' failed %v (output %q)", err, out)
-		}
-		fmt.Printf("\n\"kubectl version\" output:\n%s\n", out)
-
-		ts.cfg.Logger.Warn("create ConfigMap failed", zap.Error(err))
+	err = kubectlutil.ApplyWithRetry(kubectlutil.ApplyConfig{
+		Logger:         ts.cfg.Logger,
+		KubectlPath:    ts.cfg.EKSConfig.KubectlPath,
+		KubeConfigPath: ts.cfg.EKSConfig.KubeConfigPath,
+		ManifestPath:   p,
+		Deadline:       5 * time.Minute,
+		InitialDelay:   5 * time.Second,
+		MaxBackoff:     30 * time.Second,
+		Stopc:          ts.cfg.Stopc,
+		Sig:            ts.cfg.Sig,
+	})
+	if err != nil {
 		ts.cfg.EKSConfig.RecordStatus(fmt.Sprintf("create ConfigMap failed (%v)", err))
+		return err
 	}
 	ts.cfg.Logger.Info("created ConfigMap")
 
@@ -63,25 +73,136 @@ metadata:
   namespace: kube-system
 data:
   mapRoles: |
-    - rolearn: {{.NGInstanceRoleARN}}
-      %s
-      groups:
-      - system:bootstrappers
-      - system:nodes
+{{.MapRoles}}
+  mapUsers: |
+{{.MapUsers}}
 `
 
-type configMapAuth struct {
-	NGInstanceRoleARN string
+type configMapAuthData struct {
+	MapRoles string
+	MapUsers string
 }
 
-func writeConfigMapAuth(arn string) (p string, err error) {
-	kc := configMapAuth{NGInstanceRoleARN: arn}
+// mergeConfigMapAuth computes the full set of role/user mappings to apply:
+// the node group's own instance role, any extra mappings configured on
+// AddOnNodeGroups, and whatever is already present in the cluster's
+// aws-auth ConfigMap (so re-applying never drops entries another tester
+// or operator has added).
+func (ts *tester) mergeConfigMapAuth() (roles []RoleMapping, users []UserMapping, err error) {
+	roles = append(roles, RoleMapping{
+		RoleARN:  ts.cfg.EKSConfig.AddOnNodeGroups.RoleARN,
+		Username: "system:node:{{EC2PrivateDNSName}}",
+		Groups:   []string{"system:bootstrappers", "system:nodes"},
+	})
+	roles = append(roles, ts.cfg.EKSConfig.AddOnNodeGroups.MapRoles...)
+	users = append(users, ts.cfg.EKSConfig.AddOnNodeGroups.MapUsers...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	output, getErr := exec.New().CommandContext(
+		ctx,
+		ts.cfg.EKSConfig.KubectlPath,
+		"--kubeconfig="+ts.cfg.EKSConfig.KubeConfigPath,
+		"get", "configmap", "aws-auth", "--namespace=kube-system", "--output=yaml",
+	).CombinedOutput()
+	cancel()
+	if getErr != nil {
+		if !isNotFoundErr(getErr, output) {
+			return nil, nil, fmt.Errorf("failed to get existing aws-auth ConfigMap (%v)", getErr)
+		}
+		// aws-auth ConfigMap does not exist yet, nothing to merge
+		ts.cfg.Logger.Info("aws-auth ConfigMap not found, creating fresh", zap.Error(getErr))
+		return roles, users, nil
+	}
+
+	var existing struct {
+		Data map[string]string `json:"data"`
+	}
+	if err = yaml.Unmarshal(output, &existing); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse existing aws-auth ConfigMap (%v)", err)
+	}
+
+	var existingRoles []RoleMapping
+	if v, ok := existing.Data["mapRoles"]; ok {
+		if err = yaml.Unmarshal([]byte(v), &existingRoles); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse existing mapRoles (%v)", err)
+		}
+	}
+	var existingUsers []UserMapping
+	if v, ok := existing.Data["mapUsers"]; ok {
+		if err = yaml.Unmarshal([]byte(v), &existingUsers); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse existing mapUsers (%v)", err)
+		}
+	}
+
+	return mergeRoleMappings(existingRoles, roles), mergeUserMappings(existingUsers, users), nil
+}
+
+// isNotFoundErr reports whether getErr/out represent kubectl's "not found"
+// signal for the aws-auth ConfigMap, as opposed to some other failure
+// (timeout, flaky API server, RBAC) that should not be treated as "create
+// fresh" since doing so would silently drop every existing entry.
+func isNotFoundErr(getErr error, out []byte) bool {
+	msg := strings.ToLower(getErr.Error() + string(out))
+	return strings.Contains(msg, "notfound") || strings.Contains(msg, "not found")
+}
+
+// mergeRoleMappings combines existing entries with additions, keeping the
+// first mapping seen for a given RoleARN rather than overwriting it.
+func mergeRoleMappings(existing, additions []RoleMapping) []RoleMapping {
+	seen := make(map[string]struct{}, len(existing)+len(additions))
+	merged := make([]RoleMapping, 0, len(existing)+len(additions))
+	for _, r := range append(existing, additions...) {
+		if _, ok := seen[r.RoleARN]; ok {
+			continue
+		}
+		seen[r.RoleARN] = struct{}{}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// mergeUserMappings combines existing entries with additions, keeping the
+// first mapping seen for a given UserARN rather than overwriting it.
+func mergeUserMappings(existing, additions []UserMapping) []UserMapping {
+	seen := make(map[string]struct{}, len(existing)+len(additions))
+	merged := make([]UserMapping, 0, len(existing)+len(additions))
+	for _, u := range append(existing, additions...) {
+		if _, ok := seen[u.UserARN]; ok {
+			continue
+		}
+		seen[u.UserARN] = struct{}{}
+		merged = append(merged, u)
+	}
+	return merged
+}
+
+func writeConfigMapAuth(roles []RoleMapping, users []UserMapping) (p string, err error) {
+	rolesYAML, err := yaml.Marshal(roles)
+	if err != nil {
+		return "", err
+	}
+	usersYAML, err := yaml.Marshal(users)
+	if err != nil {
+		return "", err
+	}
+	kc := configMapAuthData{
+		MapRoles: indentBlock(string(rolesYAML), "    "),
+		MapUsers: indentBlock(string(usersYAML), "    "),
+	}
 	tpl := template.Must(template.New("configMapAuthTempl").Parse(configMapAuthTempl))
 	buf := bytes.NewBuffer(nil)
 	if err = tpl.Execute(buf, kc); err != nil {
 		return "", err
 	}
-	// avoid '{{' conflicts with Go
-	txt := fmt.Sprintf(buf.String(), `username: system:node:{{EC2PrivateDNSName}}`)
-	return fileutil.WriteTempFile([]byte(txt))
+	return fileutil.WriteTempFile(buf.Bytes())
+}
+
+// indentBlock prefixes every line of s with prefix, for embedding a YAML
+// document as a literal block scalar inside the ConfigMap template.
+func indentBlock(s string, prefix string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = prefix + l
+	}
+	return strings.Join(lines, "\n")
 }
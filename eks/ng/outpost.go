@@ -0,0 +1,55 @@
+package ng
+
+import "github.com/aws/aws-k8s-tester/pkg/arnutil"
+
+// OutpostTopologyLabel is the EBS CSI driver's topology label for
+// restricting volume creation to a single Outpost, matching the label the
+// driver already uses for AZ-scoped volumes. It is not yet applied to
+// nodes: doing so needs the node-bootstrap/kubelet-labels path, which does
+// not exist in this tree. Whatever wires node labels for this node group
+// should set it from the OutpostARN's resource ID.
+const OutpostTopologyLabel = "topology.ebs.csi.aws.com/outpost-id"
+
+// validateOutpostARNs checks the OutpostARN of every ASG configured to run
+// on an Outpost, and fails fast if the node group pairs an Outpost with an
+// add-on that Outposts does not support (e.g. an NLB-backed add-on, since
+// Outposts subnets have no public NLB target).
+//
+// This only validates the ARN itself (partition/region/service) and
+// add-on compatibility. It does not derive the Outpost's AZ or confirm the
+// configured subnet actually lives on the Outpost, both of which need a
+// DescribeOutposts round trip that has no client plumbed into this tester
+// yet; callers should not rely on this function for that guarantee.
+func (ts *tester) validateOutpostARNs() error {
+	for name, asg := range ts.cfg.EKSConfig.AddOnNodeGroups.ASGs {
+		if asg.OutpostARN == "" {
+			continue
+		}
+
+		req, err := arnutil.NewRequest(asg.OutpostARN, ts.cfg.EKSConfig.Region, ts.cfg.EKSConfig.AddOnNodeGroups.AllowCrossRegionARN)
+		if err != nil {
+			return err
+		}
+		if req.ARN.Service != "outposts" {
+			return &outpostARNError{asg: name, arn: asg.OutpostARN, reason: "is not an Outposts ARN"}
+		}
+		if err = req.Validate(); err != nil {
+			return err
+		}
+
+		if ts.cfg.EKSConfig.AddOnNLBHelloWorld.Enable {
+			return &outpostARNError{asg: name, arn: asg.OutpostARN, reason: "does not support the NLB hello-world add-on"}
+		}
+	}
+	return nil
+}
+
+type outpostARNError struct {
+	asg    string
+	arn    string
+	reason string
+}
+
+func (e *outpostARNError) Error() string {
+	return "ASG " + e.asg + " OutpostARN " + e.arn + " " + e.reason
+}
@@ -0,0 +1,22 @@
+package ng
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-k8s-tester/pkg/ebsutil"
+)
+
+// validateASGVolumes checks the EBS volume settings of every configured ASG
+// and fills in the gp3 baseline (minimum IOPS/throughput) when left unset.
+// It is called before any CloudFormation/launch-template rendering so that
+// a bad combination fails at config-validation time rather than mid-create.
+func (ts *tester) validateASGVolumes() error {
+	for name, asg := range ts.cfg.EKSConfig.AddOnNodeGroups.ASGs {
+		asg.VolumeIOPS, asg.VolumeThroughput = ebsutil.DefaultGP3IOPSThroughput(asg.VolumeType, asg.VolumeIOPS, asg.VolumeThroughput)
+		if err := ebsutil.Validate(fmt.Sprintf("ASG %q", name), asg.VolumeType, asg.VolumeIOPS, asg.VolumeThroughput); err != nil {
+			return err
+		}
+		ts.cfg.EKSConfig.AddOnNodeGroups.ASGs[name] = asg
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package arnutil validates ARNs that the tester consumes from user
+// config (node group RoleARN, KMS keys, ACM certs, Secrets Manager ARNs,
+// S3 bucket ARNs, ...) against the cluster's configured region.
+//
+// It generalizes the cross-partition/cross-region checks that
+// aws-sdk-go-v2's internal s3shared.ResourceRequest performs for S3 ARNs.
+package arnutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	awsarn "github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// ErrCrossPartitionARN is returned when an ARN's partition does not match
+// the partition resolved from the configured region.
+var ErrCrossPartitionARN = errors.New("arnutil: ARN is in a different AWS partition than the configured region")
+
+// ErrCrossRegionARN is returned when an ARN's region does not match the
+// configured region, and the caller has not opted in to cross-region ARNs.
+var ErrCrossRegionARN = errors.New("arnutil: ARN is in a different AWS region than the configured region")
+
+// Request is a parsed ARN plus the context needed to validate it against
+// the tester's configured region, mirroring s3shared.ResourceRequest.
+type Request struct {
+	ARN awsarn.ARN
+	// Region is the region configured for the tester/cluster.
+	Region string
+	// PartitionID is the partition resolved from Region.
+	PartitionID string
+	// AllowCrossRegion mirrors S3's "S3UseARNRegion"/"AllowCrossRegion"
+	// escape hatch: true to permit an ARN whose region differs from Region.
+	AllowCrossRegion bool
+}
+
+// NewRequest parses arnString and resolves PartitionID from region.
+func NewRequest(arnString, region string, allowCrossRegion bool) (Request, error) {
+	parsed, err := awsarn.Parse(arnString)
+	if err != nil {
+		return Request{}, fmt.Errorf("arnutil: invalid ARN %q (%v)", arnString, err)
+	}
+	return Request{
+		ARN:              parsed,
+		Region:           region,
+		PartitionID:      PartitionForRegion(region),
+		AllowCrossRegion: allowCrossRegion,
+	}, nil
+}
+
+// Validate returns ErrCrossPartitionARN/ErrCrossRegionARN as appropriate.
+// Cross-partition ARNs are always rejected; cross-region ARNs are rejected
+// unless AllowCrossRegion is set.
+func (r Request) Validate() error {
+	if !strings.EqualFold(r.PartitionID, r.ARN.Partition) {
+		return fmt.Errorf("%w (region %q resolves to partition %q, ARN %q is in partition %q)",
+			ErrCrossPartitionARN, r.Region, r.PartitionID, r.ARN.String(), r.ARN.Partition)
+	}
+	if r.ARN.Region != "" && !r.AllowCrossRegion && !strings.EqualFold(r.Region, r.ARN.Region) {
+		return fmt.Errorf("%w (configured region %q, ARN %q is in region %q)",
+			ErrCrossRegionARN, r.Region, r.ARN.String(), r.ARN.Region)
+	}
+	return nil
+}
+
+// ValidateARN is a convenience wrapper around NewRequest+Validate for
+// config-validation call sites that just want a single error check.
+func ValidateARN(arnString, region string, allowCrossRegion bool) error {
+	req, err := NewRequest(arnString, region, allowCrossRegion)
+	if err != nil {
+		return err
+	}
+	return req.Validate()
+}
+
+// PartitionForRegion resolves the AWS partition ID for a region name.
+func PartitionForRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "us-iso-"):
+		return "aws-iso"
+	case strings.HasPrefix(region, "us-isob-"):
+		return "aws-iso-b"
+	default:
+		return "aws"
+	}
+}
@@ -0,0 +1,55 @@
+// Package awsapi builds shared AWS SDK client configuration for the
+// tester, such as picking FIPS-compliant endpoints when required.
+package awsapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StripFIPS removes the "fips-"/"-fips" decoration from a region name,
+// returning the underlying AWS region (e.g. "fips-us-east-1" -> "us-east-1").
+func StripFIPS(region string) string {
+	region = strings.TrimPrefix(region, "fips-")
+	region = strings.TrimSuffix(region, "-fips")
+	return region
+}
+
+// fipsEKSRegions is the set of regions that publish a FIPS endpoint for
+// EKS. Other services in play (EC2, IAM, CloudFormation, S3, SSM) have
+// wider FIPS coverage, so EKS is what gates whether FIPS mode can be
+// enabled at all for a given region.
+var fipsEKSRegions = map[string]struct{}{
+	"us-east-1":     {},
+	"us-east-2":     {},
+	"us-west-1":     {},
+	"us-west-2":     {},
+	"us-gov-east-1": {},
+	"us-gov-west-1": {},
+}
+
+// ValidateFIPSEndpointRegion returns an error if useFIPS is true and region
+// (a plain, undecorated region name such as "us-east-1") does not offer a
+// FIPS EKS endpoint. FIPS is tracked as a separate config flag alongside a
+// plain region rather than via a "fips-"/"-fips" naming convention on the
+// region itself.
+func ValidateFIPSEndpointRegion(region string, useFIPS bool) error {
+	if !useFIPS {
+		return nil
+	}
+	if _, ok := fipsEKSRegions[region]; !ok {
+		return fmt.Errorf("region %q does not offer a FIPS EKS endpoint", region)
+	}
+	return nil
+}
+
+// EndpointFor returns the regional endpoint hostname for the given service
+// (e.g. "eks", "ec2", "iam", "cloudformation", "s3", "ssm"), using the FIPS
+// variant when useFIPS is true.
+func EndpointFor(service, region string, useFIPS bool) string {
+	region = StripFIPS(region)
+	if !useFIPS {
+		return fmt.Sprintf("https://%s.%s.amazonaws.com", service, region)
+	}
+	return fmt.Sprintf("https://%s-fips.%s.amazonaws.com", service, region)
+}
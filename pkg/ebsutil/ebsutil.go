@@ -0,0 +1,59 @@
+// Package ebsutil validates EBS volume type/IOPS/throughput combinations
+// against the constraints the EBS CreateVolume API enforces. It is shared
+// by ec2config's BlockDeviceMappings validation and eks/ng's ASG volume
+// validation so the gp3/io1/io2 rules live in exactly one place.
+package ebsutil
+
+import "fmt"
+
+// gp3 IOPS/throughput constraints, mirrored from the EBS CreateVolume API.
+const (
+	MinGP3IOPS       = 3000
+	MaxGP3IOPS       = 16000
+	MinGP3Throughput = 125
+	MaxGP3Throughput = 1000
+)
+
+// Validate rejects VolumeType/Iops/Throughput combinations the EBS
+// CreateVolume API would reject. label identifies the volume (an ASG name,
+// a device name, ...) in the returned error.
+func Validate(label, volumeType string, iops, throughput int64) error {
+	switch volumeType {
+	case "gp3":
+		if iops != 0 && (iops < MinGP3IOPS || iops > MaxGP3IOPS) {
+			return fmt.Errorf("%s gp3 Iops %d out of range [%d, %d]", label, iops, MinGP3IOPS, MaxGP3IOPS)
+		}
+		if throughput != 0 && (throughput < MinGP3Throughput || throughput > MaxGP3Throughput) {
+			return fmt.Errorf("%s gp3 Throughput %d out of range [%d, %d]", label, throughput, MinGP3Throughput, MaxGP3Throughput)
+		}
+	case "io1", "io2":
+		if iops == 0 {
+			return fmt.Errorf("%s volume type %q requires Iops", label, volumeType)
+		}
+		if throughput != 0 {
+			return fmt.Errorf("%s volume type %q does not support Throughput", label, volumeType)
+		}
+	case "", "gp2", "st1", "sc1", "standard":
+		if iops != 0 || throughput != 0 {
+			return fmt.Errorf("%s volume type %q does not support Iops/Throughput", label, volumeType)
+		}
+	default:
+		return fmt.Errorf("%s has unknown VolumeType %q", label, volumeType)
+	}
+	return nil
+}
+
+// DefaultGP3IOPSThroughput fills in the gp3 baseline (minimum IOPS and
+// throughput) when a gp3 volume leaves them unset.
+func DefaultGP3IOPSThroughput(volumeType string, iops, throughput int64) (int64, int64) {
+	if volumeType != "gp3" {
+		return iops, throughput
+	}
+	if iops == 0 {
+		iops = MinGP3IOPS
+	}
+	if throughput == 0 {
+		throughput = MinGP3Throughput
+	}
+	return iops, throughput
+}
@@ -0,0 +1,26 @@
+package ebsutil
+
+import "testing"
+
+func TestValidateGP3NonDefaultIOPSThroughput(t *testing.T) {
+	if err := Validate("test", "gp3", 6000, 250); err != nil {
+		t.Fatalf("expected non-default gp3 Iops/Throughput within range to be accepted, got %v", err)
+	}
+	if err := Validate("test", "gp3", MaxGP3IOPS+1, MinGP3Throughput); err == nil {
+		t.Fatal("expected out-of-range gp3 Iops to be rejected")
+	}
+	if err := Validate("test", "gp3", MinGP3IOPS, MaxGP3Throughput+1); err == nil {
+		t.Fatal("expected out-of-range gp3 Throughput to be rejected")
+	}
+}
+
+func TestDefaultGP3IOPSThroughput(t *testing.T) {
+	iops, throughput := DefaultGP3IOPSThroughput("gp3", 0, 0)
+	if iops != MinGP3IOPS || throughput != MinGP3Throughput {
+		t.Fatalf("expected gp3 baseline defaults, got iops=%d throughput=%d", iops, throughput)
+	}
+	iops, throughput = DefaultGP3IOPSThroughput("gp3", 6000, 250)
+	if iops != 6000 || throughput != 250 {
+		t.Fatalf("expected explicit non-default values to be preserved, got iops=%d throughput=%d", iops, throughput)
+	}
+}
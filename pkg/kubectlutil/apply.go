@@ -0,0 +1,156 @@
+// Package kubectlutil provides shared helpers for tester subsystems that
+// shell out to kubectl.
+package kubectlutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/utils/exec"
+)
+
+// ApplyConfig configures a retrying "kubectl apply" invocation.
+type ApplyConfig struct {
+	Logger *zap.Logger
+
+	KubectlPath    string
+	KubeConfigPath string
+	ManifestPath   string
+
+	// Deadline is the total time allowed across all attempts.
+	Deadline time.Duration
+	// InitialDelay is the delay before the first attempt, and the starting
+	// point for exponential backoff between retries.
+	InitialDelay time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+
+	// Stopc, if closed, aborts the retry loop.
+	Stopc chan struct{}
+	// Sig, if it receives a signal, aborts the retry loop.
+	Sig chan os.Signal
+}
+
+// transientSubstrings are substrings of "kubectl apply" output/errors that
+// indicate a retryable, transient failure (DNS/API server unreachable, TLS
+// handshake issues) rather than a permanent one.
+var transientSubstrings = []string{
+	"no such host",
+	"connection refused",
+	"i/o timeout",
+	"tls handshake timeout",
+	"dial tcp",
+	"unable to connect to the server",
+	"client connection lost",
+	"eof",
+}
+
+// permanentSubstrings short-circuits the retry loop for errors that will
+// never succeed by simply waiting and trying again.
+var permanentSubstrings = []string{
+	"forbidden",
+	"unauthorized",
+	"error validating",
+	"is invalid",
+	"no matches for kind",
+	"unable to recognize",
+}
+
+// ApplyWithRetry runs "kubectl apply --filename=<ManifestPath>" until it is
+// accepted by the API server, a permanent error is detected, Deadline
+// elapses, or the tester is asked to stop via Stopc/Sig.
+func ApplyWithRetry(cfg ApplyConfig) error {
+	delay := cfg.InitialDelay
+	if delay <= 0 {
+		delay = 5 * time.Second
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = time.Minute
+	}
+
+	deadline := time.Now().Add(cfg.Deadline)
+	for time.Now().Before(deadline) {
+		select {
+		case <-cfg.Stopc:
+			return errors.New("kubectl apply aborted")
+		case <-cfg.Sig:
+			return errors.New("kubectl apply aborted")
+		case <-time.After(delay):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		output, err := exec.New().CommandContext(
+			ctx,
+			cfg.KubectlPath,
+			"--kubeconfig="+cfg.KubeConfigPath,
+			"apply", "--filename="+cfg.ManifestPath,
+		).CombinedOutput()
+		cancel()
+		out := string(output)
+
+		if err == nil && applyAccepted(out) {
+			if cfg.Logger != nil {
+				cfg.Logger.Info("kubectl apply accepted", zap.String("output", out))
+			}
+			return nil
+		}
+
+		if isPermanent(err, out) {
+			return fmt.Errorf("kubectl apply failed permanently %v (output %q)", err, out)
+		}
+
+		if cfg.Logger != nil {
+			cfg.Logger.Warn("kubectl apply failed, retrying", zap.Error(err), zap.String("output", out))
+		}
+
+		if delay < maxBackoff {
+			delay *= 2
+			if delay > maxBackoff {
+				delay = maxBackoff
+			}
+		}
+	}
+
+	return fmt.Errorf("kubectl apply did not succeed within %v", cfg.Deadline)
+}
+
+// applyAccepted parses "kubectl apply" output for the per-resource verbs it
+// prints once the API server has accepted a manifest (e.g.
+// "configmap/aws-auth configured").
+func applyAccepted(out string) bool {
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasSuffix(line, "created") ||
+			strings.HasSuffix(line, "configured") ||
+			strings.HasSuffix(line, "unchanged") {
+			return true
+		}
+	}
+	return false
+}
+
+// isPermanent returns true if err/out indicate a failure that will never
+// resolve itself by retrying (bad manifest, forbidden, etc.).
+func isPermanent(err error, out string) bool {
+	if err == nil {
+		return false
+	}
+	combined := strings.ToLower(out + " " + err.Error())
+	for _, s := range transientSubstrings {
+		if strings.Contains(combined, s) {
+			return false
+		}
+	}
+	for _, s := range permanentSubstrings {
+		if strings.Contains(combined, s) {
+			return true
+		}
+	}
+	return false
+}